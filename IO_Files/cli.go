@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// cliFlags agrupa las banderas comunes a los cuatro subcomandos
+// (process/report/validate/stats), siguiendo el patrón flag.NewFlagSet +
+// flag.Args() para leer también el argumento posicional ("-" = stdin).
+type cliFlags struct {
+	inventory         string
+	transactions      string
+	outputDir         string
+	lowStockThreshold int
+	delimiter         string
+	lazyQuotes        bool
+	fieldsPerRecord   int
+	backend           string
+	sqlitePath        string
+	verbose           bool
+	logFormat         string
+	logMaxBytes       int64
+	dedupePath        string
+	resetDedupe       bool
+}
+
+// parseCLIFlags registra las banderas comunes en fs, lo parsea y, si queda un
+// argumento posicional (por ejemplo "-" para leer transacciones de stdin), lo
+// usa como ruta de transacciones.
+func parseCLIFlags(fs *flag.FlagSet, args []string) (*cliFlags, error) {
+	cf := &cliFlags{}
+	fs.StringVar(&cf.inventory, "inventory", "inventario.txt", "ruta del inventario CSV")
+	fs.StringVar(&cf.transactions, "transactions", "transacciones.txt", "ruta de transacciones CSV, o '-' para stdin")
+	fs.StringVar(&cf.outputDir, "output-dir", ".", "directorio donde escribir reporte y log")
+	fs.IntVar(&cf.lowStockThreshold, "low-stock-threshold", 10, "umbral de stock bajo para el reporte")
+	fs.StringVar(&cf.delimiter, "delimiter", ",", "separador de campos CSV")
+	fs.BoolVar(&cf.lazyQuotes, "lazy-quotes", false, "tolera comillas no estándar (csv.Reader.LazyQuotes)")
+	fs.IntVar(&cf.fieldsPerRecord, "fields-per-record", 0, "nº de campos esperado por fila; -1 tolera CSVs irregulares")
+	fs.StringVar(&cf.backend, "backend", "csv", "backend de almacenamiento: csv o sqlite")
+	fs.StringVar(&cf.sqlitePath, "sqlite-path", "inventario.db", "ruta de la base SQLite cuando --backend=sqlite")
+	fs.BoolVar(&cf.verbose, "verbose", false, "espeja los mensajes de log en stderr")
+	fs.StringVar(&cf.logFormat, "log-format", "text", "formato del log de errores: text o json")
+	fs.Int64Var(&cf.logMaxBytes, "log-max-bytes", 5*1024*1024, "tamaño máximo de errores.log antes de rotar")
+	fs.StringVar(&cf.dedupePath, "dedupe-path", "applied_transactions.db", "archivo donde se recuerdan las transacciones ya aplicadas")
+	fs.BoolVar(&cf.resetDedupe, "reset-dedupe", false, "olvida qué transacciones se aplicaron antes de procesar")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		cf.transactions = fs.Arg(0)
+	}
+	return cf, nil
+}
+
+// csvOptions traduce las banderas de formato CSV a las CSVOption que entienden
+// los Stream*.
+func (cf *cliFlags) csvOptions() []CSVOption {
+	var opts []CSVOption
+	if cf.delimiter != "" {
+		opts = append(opts, WithDelimiter([]rune(cf.delimiter)[0]))
+	}
+	if cf.lazyQuotes {
+		opts = append(opts, WithLazyQuotes(true))
+	}
+	if cf.fieldsPerRecord != 0 {
+		opts = append(opts, WithFieldsPerRecord(cf.fieldsPerRecord))
+	}
+	return opts
+}
+
+// cmdProcess es el flujo completo de siempre: leer inventario, aplicar
+// transacciones, guardar inventario, generar reporte de bajo stock y log de
+// errores. Es lo que antes hacía main() directamente.
+func cmdProcess(args []string) error {
+	fs := flag.NewFlagSet("process", flag.ContinueOnError)
+	cf, err := parseCLIFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	opts := cf.csvOptions()
+
+	var erroresRecuperados []ErrorProceso
+	if cf.backend == "csv" {
+		erroresRecuperados, err = Recover(cf.inventory, cf.inventory+".wal")
+		if err != nil {
+			return fmt.Errorf("fallo recuperando WAL: %w", err)
+		}
+	}
+
+	dedupe, err := OpenDedupeCache(cf.dedupePath)
+	if err != nil {
+		return fmt.Errorf("fallo abriendo caché de dedupe: %w", err)
+	}
+	if cf.resetDedupe {
+		dedupe.Reset()
+	}
+
+	repo, cerrar, err := abrirRepositorio(cf.backend, cf.inventory, cf.transactions, cf.sqlitePath)
+	if err != nil {
+		return fmt.Errorf("fallo preparando backend: %w", err)
+	}
+	defer cerrar()
+
+	productos, err := repo.LoadProductos()
+	if err != nil {
+		return fmt.Errorf("fallo leyendo inventario: %w", err)
+	}
+
+	errores := erroresRecuperados
+	if cf.backend == "csv" {
+		nuevosErrores, err := procesarTransaccionesConWAL(cf.inventory, cf.transactions, productos, dedupe, opts...)
+		if err != nil {
+			return fmt.Errorf("fallo procesando transacciones con WAL: %w", err)
+		}
+		errores = append(errores, nuevosErrores...)
+	} else {
+		errores = append(errores, procesarTransaccionesPipeline(context.Background(), productos, cf.transactions, runtime.NumCPU(), dedupe, opts...)...)
+		if err := repo.SaveProductos(productos); err != nil {
+			return fmt.Errorf("error guardando inventario actualizado: %w", err)
+		}
+	}
+
+	if err := dedupe.Save(); err != nil {
+		return fmt.Errorf("fallo guardando caché de dedupe: %w", err)
+	}
+
+	if err := os.MkdirAll(cf.outputDir, 0755); err != nil {
+		return fmt.Errorf("no se pudo crear output-dir: %w", err)
+	}
+	if err := generarReporteBajoStock(productos, cf.lowStockThreshold, cf.outputDir); err != nil {
+		return fmt.Errorf("error generando reporte bajo stock: %w", err)
+	}
+	logPath := filepath.Join(cf.outputDir, "errores.log")
+	if err := escribirLog(errores, logPath, cf.verbose, cf.logFormat, cf.logMaxBytes); err != nil {
+		return fmt.Errorf("error escribiendo log de errores: %w", err)
+	}
+
+	fmt.Println("Proceso completado.")
+	fmt.Println("Inventario actualizado via backend:", cf.backend)
+	fmt.Println("Reporte bajo stock ->", filepath.Join(cf.outputDir, "productos_bajo_stock.txt"))
+	fmt.Println("Errores (si los hay) ->", logPath)
+	return nil
+}
+
+// cmdReport solo (re)genera el reporte de bajo stock a partir del inventario
+// actual, sin tocar transacciones.
+func cmdReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	cf, err := parseCLIFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	productos, err := leerInventario(cf.inventory, cf.csvOptions()...)
+	if err != nil {
+		return fmt.Errorf("fallo leyendo inventario: %w", err)
+	}
+	if err := os.MkdirAll(cf.outputDir, 0755); err != nil {
+		return fmt.Errorf("no se pudo crear output-dir: %w", err)
+	}
+	if err := generarReporteBajoStock(productos, cf.lowStockThreshold, cf.outputDir); err != nil {
+		return fmt.Errorf("error generando reporte bajo stock: %w", err)
+	}
+	fmt.Println("Reporte bajo stock ->", filepath.Join(cf.outputDir, "productos_bajo_stock.txt"))
+	return nil
+}
+
+// cmdValidate lee inventario y transacciones y reporta el primer error de
+// parseo que encuentre, sin escribir ningún archivo de salida.
+func cmdValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	cf, err := parseCLIFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	opts := cf.csvOptions()
+
+	productos, err := leerInventario(cf.inventory, opts...)
+	if err != nil {
+		return fmt.Errorf("inventario inválido: %w", err)
+	}
+	transacciones, err := leerTransacciones(cf.transactions, opts...)
+	if err != nil {
+		return fmt.Errorf("transacciones inválidas: %w", err)
+	}
+
+	fmt.Printf("OK: %d productos, %d transacciones bien formadas\n", len(productos), len(transacciones))
+	return nil
+}
+
+// cmdStats imprime un resumen rápido del inventario: nº de productos, stock
+// total y cuántos están por debajo del umbral de bajo stock.
+func cmdStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	cf, err := parseCLIFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	productos, err := leerInventario(cf.inventory, cf.csvOptions()...)
+	if err != nil {
+		return fmt.Errorf("fallo leyendo inventario: %w", err)
+	}
+
+	stockTotal := 0
+	bajoStock := 0
+	for _, p := range productos {
+		stockTotal += p.Stock
+		if p.Stock < cf.lowStockThreshold {
+			bajoStock++
+		}
+	}
+
+	fmt.Printf("Productos: %d\n", len(productos))
+	fmt.Printf("Stock total: %d\n", stockTotal)
+	fmt.Printf("Productos con bajo stock (< %d): %d\n", cf.lowStockThreshold, bajoStock)
+	return nil
+}
+
+// cmdDedupeStats implementa "psp dedupe stats": imprime cuántas transacciones
+// distintas recuerda el caché y sus aciertos/fallos históricos, sin tocar el
+// inventario ni las transacciones.
+func cmdDedupeStats(args []string) error {
+	fs := flag.NewFlagSet("dedupe stats", flag.ContinueOnError)
+	dedupePath := fs.String("dedupe-path", "applied_transactions.db", "archivo donde se recuerdan las transacciones ya aplicadas")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	dedupe, err := OpenDedupeCache(*dedupePath)
+	if err != nil {
+		return fmt.Errorf("fallo abriendo caché de dedupe: %w", err)
+	}
+
+	entradas, hits, misses := dedupe.Stats()
+	fmt.Printf("Transacciones recordadas: %d\n", entradas)
+	fmt.Printf("Hits (descartadas por repetidas): %d\n", hits)
+	fmt.Printf("Misses (aplicadas por primera vez): %d\n", misses)
+	return nil
+}
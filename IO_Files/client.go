@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// cmdClient implementa "psp client <productos|producto|vender|comprar|devolver|bajo-stock>",
+// un cliente HTTP mínimo para el servidor de cmdServer: solo net/http y
+// encoding/json, sin generar el código a partir del spec OpenAPI (ver
+// openapi.yaml).
+func cmdClient(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("uso: psp client <productos|producto|vender|comprar|devolver|bajo-stock> [opciones]")
+	}
+
+	switch args[0] {
+	case "productos":
+		return cmdClientProductos(args[1:])
+	case "producto":
+		return cmdClientProducto(args[1:])
+	case "vender":
+		return cmdClientTransaccion(args[1:], "VENTA")
+	case "comprar":
+		return cmdClientTransaccion(args[1:], "COMPRA")
+	case "devolver":
+		return cmdClientTransaccion(args[1:], "DEVOLUCION")
+	case "bajo-stock":
+		return cmdClientBajoStock(args[1:])
+	default:
+		return fmt.Errorf("subcomando de client desconocido: %q", args[0])
+	}
+}
+
+// clientFlags agrupa la bandera común a todos los subcomandos de client: la
+// URL base del servidor levantado con "psp server".
+type clientFlags struct {
+	addr string
+}
+
+func parseClientFlags(fs *flag.FlagSet, args []string) (*clientFlags, error) {
+	cf := &clientFlags{}
+	fs.StringVar(&cf.addr, "addr", "http://localhost:8080", "URL base del servidor psp")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+func cmdClientProductos(args []string) error {
+	fs := flag.NewFlagSet("client productos", flag.ContinueOnError)
+	cf, err := parseClientFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	return clientGetYImprimir(cf.addr + "/productos")
+}
+
+func cmdClientProducto(args []string) error {
+	fs := flag.NewFlagSet("client producto", flag.ContinueOnError)
+	cf, err := parseClientFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("uso: psp client producto [--addr url] <id>")
+	}
+	return clientGetYImprimir(cf.addr + "/productos/" + fs.Arg(0))
+}
+
+func cmdClientBajoStock(args []string) error {
+	fs := flag.NewFlagSet("client bajo-stock", flag.ContinueOnError)
+	limit := fs.Int("limit", 10, "umbral de stock bajo")
+	cf, err := parseClientFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	return clientGetYImprimir(fmt.Sprintf("%s/reportes/bajo-stock?limit=%d", cf.addr, *limit))
+}
+
+// cmdClientTransaccion hace POST /transacciones con tipo fijo y los
+// argumentos posicionales <id-producto> <cantidad> [fecha].
+func cmdClientTransaccion(args []string, tipo string) error {
+	fs := flag.NewFlagSet("client "+tipo, flag.ContinueOnError)
+	cf, err := parseClientFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return fmt.Errorf("uso: psp client %s [--addr url] <id-producto> <cantidad> [fecha]", tipo)
+	}
+	var cantidad int
+	if _, err := fmt.Sscanf(fs.Arg(1), "%d", &cantidad); err != nil {
+		return fmt.Errorf("cantidad inválida %q: %w", fs.Arg(1), err)
+	}
+	fecha := ""
+	if fs.NArg() >= 3 {
+		fecha = fs.Arg(2)
+	}
+
+	cuerpo, err := json.Marshal(Transaccion{Tipo: tipo, IDProducto: fs.Arg(0), Cantidad: cantidad, Fecha: fecha})
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(cf.addr+"/transacciones", "application/json", bytes.NewReader(cuerpo))
+	if err != nil {
+		return fmt.Errorf("fallo llamando al servidor: %w", err)
+	}
+	defer resp.Body.Close()
+	return imprimirRespuesta(resp)
+}
+
+func clientGetYImprimir(url string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("fallo llamando al servidor: %w", err)
+	}
+	defer resp.Body.Close()
+	return imprimirRespuesta(resp)
+}
+
+// imprimirRespuesta reimprime el cuerpo JSON de resp con sangría, o el error
+// HTTP si el servidor respondió con un código de error.
+func imprimirRespuesta(resp *http.Response) error {
+	cuerpo, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("fallo leyendo respuesta: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("servidor respondió %s: %s", resp.Status, bytes.TrimSpace(cuerpo))
+	}
+
+	var indentado bytes.Buffer
+	if err := json.Indent(&indentado, cuerpo, "", "  "); err != nil {
+		// No todo lo que devuelve el servidor es JSON indentable (p. ej. un
+		// cuerpo vacío); en ese caso se imprime tal cual.
+		fmt.Println(string(cuerpo))
+		return nil
+	}
+	fmt.Println(indentado.String())
+	return nil
+}
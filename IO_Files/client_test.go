@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubRepo satisface la interfaz mínima que pide newServer, sin tocar disco.
+type stubRepo struct {
+	guardados []Producto
+}
+
+func (s *stubRepo) SaveProductos(p []Producto) error {
+	s.guardados = p
+	return nil
+}
+
+// TestCmdClientContraServidorReal levanta un servidor HTTP real (httptest)
+// sobre los mismos handlers que usa "psp server" y comprueba que los
+// subcomandos de "psp client" (productos, producto, vender, bajo-stock)
+// funcionan contra él de punta a punta, sin mockear net/http.
+func TestCmdClientContraServidorReal(t *testing.T) {
+	srv := newServer([]Producto{{ID: "P1", Nombre: "Tornillo", Categoria: "Ferretería", Precio: 0.5, Stock: 10}}, &stubRepo{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/productos", srv.handleProductos)
+	mux.HandleFunc("/productos/", srv.handleProductos)
+	mux.HandleFunc("/transacciones", srv.handleTransacciones)
+	mux.HandleFunc("/reportes/bajo-stock", srv.handleReporteBajoStock)
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	if err := cmdClient([]string{"productos", "--addr", ts.URL}); err != nil {
+		t.Fatalf("client productos: %v", err)
+	}
+	if err := cmdClient([]string{"producto", "--addr", ts.URL, "P1"}); err != nil {
+		t.Fatalf("client producto: %v", err)
+	}
+	if err := cmdClient([]string{"vender", "--addr", ts.URL, "P1", "4", "2026-01-01"}); err != nil {
+		t.Fatalf("client vender: %v", err)
+	}
+
+	s := srv.snapshot()
+	if len(s) != 1 || s[0].Stock != 6 {
+		t.Fatalf("tras 'client vender P1 4', stock = %+v, quería 6", s)
+	}
+
+	if err := cmdClient([]string{"bajo-stock", "--limit", "20", "--addr", ts.URL}); err != nil {
+		t.Fatalf("client bajo-stock: %v", err)
+	}
+}
+
+// TestCmdClientProductoInexistenteDevuelveError comprueba que un 404 del
+// servidor se propaga como error en vez de imprimirse como si fuera una
+// respuesta válida.
+func TestCmdClientProductoInexistenteDevuelveError(t *testing.T) {
+	srv := newServer(nil, &stubRepo{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/productos/", srv.handleProductos)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	if err := cmdClient([]string{"producto", "--addr", ts.URL, "NOEXISTE"}); err == nil {
+		t.Fatalf("esperaba error para un producto inexistente")
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// transaccionID devuelve t.ID si viene informado, o si no un hash estable de
+// Tipo|IDProducto|Cantidad|Fecha. Esto es lo que permite reconocer "la misma"
+// transacción aunque dos ejecuciones la lean de archivos CSV distintos (p.
+// ej. el mismo día concatenado dos veces).
+//
+// LIMITACIÓN: sin un t.ID explícito, dos transacciones legítimas y distintas
+// que compartan Tipo, IDProducto, Cantidad y Fecha (dos ventas de 3 unidades
+// del mismo producto el mismo día, por ejemplo) producen el mismo hash, así
+// que la segunda se descarta como si fuera un duplicado de la primera. Para
+// evitarlo hay que traer un ID propio por transacción: StreamTransacciones
+// acepta una quinta columna CSV opcional con ese ID y, de venir, se respeta
+// en vez de recurrir al hash.
+func transaccionID(t Transaccion) string {
+	if t.ID != "" {
+		return t.ID
+	}
+	clave := fmt.Sprintf("%s|%s|%d|%s", t.Tipo, t.IDProducto, t.Cantidad, t.Fecha)
+	suma := sha256.Sum256([]byte(clave))
+	return fmt.Sprintf("%x", suma)
+}
+
+// dedupeState es lo que se persiste en el archivo de dedupe (gob): los IDs ya
+// aplicados y, para "psp dedupe stats", el total histórico de aciertos/fallos.
+type dedupeState struct {
+	Aplicadas map[string]bool
+	Hits      int
+	Misses    int
+}
+
+// DedupeCache evita aplicar dos veces la misma Transaccion entre ejecuciones:
+// antes de aplicar una se consulta aquí (AlreadyApplied), y solo se marca
+// (Mark) una vez aplicada con éxito. Se persiste en un
+// archivo gob en vez de bbolt para no añadir una dependencia externa a un
+// programa que hasta ahora no tenía ninguna más allá del driver SQL.
+type DedupeCache struct {
+	mu    sync.Mutex
+	path  string
+	state dedupeState
+}
+
+// OpenDedupeCache carga el estado desde path, o arranca uno vacío si el
+// archivo no existe todavía.
+func OpenDedupeCache(path string) (*DedupeCache, error) {
+	d := &DedupeCache{path: path, state: dedupeState{Aplicadas: make(map[string]bool)}}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := gob.NewDecoder(f).Decode(&d.state); err != nil {
+		return nil, fmt.Errorf("no se pudo leer %s: %w", path, err)
+	}
+	if d.state.Aplicadas == nil {
+		d.state.Aplicadas = make(map[string]bool)
+	}
+	return d, nil
+}
+
+// AlreadyApplied consulta si id ya se aplicó con éxito en una ejecución
+// anterior, sin marcar nada. Devuelve true cuando hay que saltarse la
+// transacción por estar repetida. A diferencia del antiguo CheckAndMark, no
+// marca id como aplicado: eso lo hace Mark, y solo debe llamarse una vez
+// confirmado que la transacción se aplicó (si no, una venta rechazada por
+// falta de stock quedaría marcada como hecha y un reproceso posterior,
+// tras reponer stock, ya no la aplicaría nunca).
+func (d *DedupeCache) AlreadyApplied(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.state.Aplicadas[id] {
+		d.state.Hits++
+		return true
+	}
+	return false
+}
+
+// Mark registra id como aplicado con éxito.
+func (d *DedupeCache) Mark(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.state.Aplicadas[id] {
+		d.state.Misses++
+	}
+	d.state.Aplicadas[id] = true
+}
+
+// Reset vacía el caché y los contadores, para --reset-dedupe.
+func (d *DedupeCache) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.state = dedupeState{Aplicadas: make(map[string]bool)}
+}
+
+// Stats devuelve (nº de transacciones recordadas, hits, misses).
+func (d *DedupeCache) Stats() (entradas, hits, misses int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.state.Aplicadas), d.state.Hits, d.state.Misses
+}
+
+// Save persiste el estado en path.
+func (d *DedupeCache) Save() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := os.Create(d.path)
+	if err != nil {
+		return fmt.Errorf("no se pudo guardar %s: %w", d.path, err)
+	}
+	defer f.Close()
+
+	if err := gob.NewEncoder(f).Encode(d.state); err != nil {
+		return fmt.Errorf("no se pudo serializar %s: %w", d.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTransaccionIDColisionaSinIDExplicito documenta la limitación señalada
+// en el doc comment de transaccionID: dos transacciones distintas que
+// comparten Tipo, IDProducto, Cantidad y Fecha producen el mismo ID cuando
+// ninguna trae un t.ID propio.
+func TestTransaccionIDColisionaSinIDExplicito(t *testing.T) {
+	a := Transaccion{Tipo: "VENTA", IDProducto: "P1", Cantidad: 3, Fecha: "2026-01-01"}
+	b := a
+	if transaccionID(a) != transaccionID(b) {
+		t.Fatalf("transacciones idénticas sin ID deberían colisionar (es la limitación documentada)")
+	}
+}
+
+// TestTransaccionIDConIDExplicitoNoColisiona comprueba que, aportando un ID
+// propio, dos transacciones que de otro modo colisionarían quedan
+// distinguidas.
+func TestTransaccionIDConIDExplicitoNoColisiona(t *testing.T) {
+	a := Transaccion{ID: "venta-1", Tipo: "VENTA", IDProducto: "P1", Cantidad: 3, Fecha: "2026-01-01"}
+	b := Transaccion{ID: "venta-2", Tipo: "VENTA", IDProducto: "P1", Cantidad: 3, Fecha: "2026-01-01"}
+	if transaccionID(a) == transaccionID(b) {
+		t.Fatalf("transacciones con ID explícito distinto no deberían colisionar")
+	}
+}
+
+// TestStreamTransaccionesLeeQuintaColumnaComoID comprueba que
+// StreamTransacciones respeta una quinta columna CSV opcional como t.ID, la
+// vía pensada para que un productor que sabe distinguir sus transacciones
+// evite la colisión de transaccionID.
+func TestStreamTransaccionesLeeQuintaColumnaComoID(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "transacciones.txt")
+	csv := "tipo,producto,cantidad,fecha,id\nVENTA,P1,3,2026-01-01,venta-1\nVENTA,P1,3,2026-01-01,venta-2\n"
+	if err := os.WriteFile(path, []byte(csv), 0644); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out, errs := StreamTransacciones(ctx, path)
+
+	var vistas []Transaccion
+	for tr := range out {
+		vistas = append(vistas, tr)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("StreamTransacciones: %v", err)
+	}
+
+	if len(vistas) != 2 || vistas[0].ID != "venta-1" || vistas[1].ID != "venta-2" {
+		t.Fatalf("transacciones leídas = %+v, quería IDs venta-1 y venta-2", vistas)
+	}
+	if transaccionID(vistas[0]) == transaccionID(vistas[1]) {
+		t.Fatalf("con ID explícito por columna, las dos transacciones no deberían colisionar")
+	}
+}
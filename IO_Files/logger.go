@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Logger sustituye el log.SetOutput global de escribirLog por un logger con
+// niveles (INFO/WARN/ERROR), rotación por tamaño y, opcionalmente, un formato
+// JSON para que herramientas externas puedan filtrar por producto.
+type Logger struct {
+	mu sync.Mutex
+
+	path       string
+	maxBytes   int64
+	format     string // "text" o "json"
+	verbose    bool
+	out        *os.File
+	info, warn *log.Logger
+	err        *log.Logger
+}
+
+// NewLogger abre (o crea) nombreArchivo en modo append -no trunca logs
+// previos- y prepara los tres niveles. Si verbose es true, cada línea también
+// se escribe en stderr.
+func NewLogger(nombreArchivo string, verbose bool, format string, maxBytes int64) (*Logger, error) {
+	f, err := os.OpenFile(nombreArchivo, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir archivo de log: %w", err)
+	}
+
+	var w io.Writer = f
+	if verbose {
+		w = io.MultiWriter(f, os.Stderr)
+	}
+
+	flags := log.LstdFlags | log.Lshortfile
+	return &Logger{
+		path:     nombreArchivo,
+		maxBytes: maxBytes,
+		format:   format,
+		verbose:  verbose,
+		out:      f,
+		info:     log.New(w, "", flags),
+		warn:     log.New(w, "", flags),
+		err:      log.New(w, "", flags),
+	}, nil
+}
+
+// Close cierra el archivo subyacente.
+func (l *Logger) Close() error {
+	return l.out.Close()
+}
+
+// Info, Warn y Error registran un mensaje asociado a un producto/tipo de
+// transacción en el nivel correspondiente.
+func (l *Logger) Info(producto, tipo, msg string)  { l.record("INFO", producto, tipo, msg) }
+func (l *Logger) Warn(producto, tipo, msg string)  { l.record("WARN", producto, tipo, msg) }
+func (l *Logger) Error(producto, tipo, msg string) { l.record("ERROR", producto, tipo, msg) }
+
+func (l *Logger) record(level, producto, tipo, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rotateSiHaceFalta()
+
+	if l.format == "json" {
+		rec := struct {
+			TS       string `json:"ts"`
+			Level    string `json:"level"`
+			Producto string `json:"producto"`
+			Tipo     string `json:"tipo"`
+			Msg      string `json:"msg"`
+		}{
+			TS:       nowRFC3339(),
+			Level:    level,
+			Producto: producto,
+			Tipo:     tipo,
+			Msg:      msg,
+		}
+		linea, err := json.Marshal(rec)
+		if err != nil {
+			l.err.Output(2, fmt.Sprintf("no se pudo serializar registro de log: %v", err))
+			return
+		}
+		l.loggerFor(level).Output(2, string(linea))
+		return
+	}
+
+	l.loggerFor(level).Output(2, fmt.Sprintf("[%s] producto=%s tipo=%s %s", level, producto, tipo, msg))
+}
+
+func (l *Logger) loggerFor(level string) *log.Logger {
+	switch level {
+	case "INFO":
+		return l.info
+	case "WARN":
+		return l.warn
+	default:
+		return l.err
+	}
+}
+
+// rotateSiHaceFalta renombra errores.log -> errores.log.1 (desplazando las
+// rotaciones anteriores) cuando el archivo supera maxBytes, y reabre uno
+// nuevo. maxBytes <= 0 desactiva la rotación.
+func (l *Logger) rotateSiHaceFalta() {
+	if l.maxBytes <= 0 {
+		return
+	}
+	info, err := l.out.Stat()
+	if err != nil || info.Size() < l.maxBytes {
+		return
+	}
+
+	l.out.Close()
+
+	for i := 9; i >= 1; i-- {
+		viejo := fmt.Sprintf("%s.%d", l.path, i)
+		nuevo := fmt.Sprintf("%s.%d", l.path, i+1)
+		if _, err := os.Stat(viejo); err == nil {
+			os.Rename(viejo, nuevo)
+		}
+	}
+	os.Rename(l.path, l.path+".1")
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		// si no podemos reabrir, seguimos escribiendo donde podamos en vez de entrar en pánico
+		f, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	}
+	l.out = f
+
+	var w io.Writer = f
+	if l.verbose {
+		w = io.MultiWriter(f, os.Stderr)
+	}
+	l.info.SetOutput(w)
+	l.warn.SetOutput(w)
+	l.err.SetOutput(w)
+}
+
+// nowRFC3339 centraliza el formato de timestamp usado en los registros JSON.
+func nowRFC3339() string {
+	return time.Now().Format(time.RFC3339)
+}
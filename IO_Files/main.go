@@ -1,125 +1,81 @@
 package main
 
 import (
+	"context"
 	"encoding/csv"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strconv"
+
+	"github.com/25xabierif/PSP/inventario"
+	"github.com/25xabierif/PSP/inventario/csvrepo"
+	"github.com/25xabierif/PSP/inventario/sqlrepo"
 )
 
 // --- Estructuras ---
-type Producto struct {
-	ID        string
-	Nombre    string
-	Categoria string
-	Precio    float64
-	Stock     int
-}
-
-func (p Producto) String() string {
-	return fmt.Sprintf("ID: %s | %s | Stock actual: %d unidades", p.ID, p.Nombre, p.Stock)
-}
-
-type Transaccion struct {
-	Tipo       string
-	IDProducto string
-	Cantidad   int
-	Fecha      string
-}
-
-func (t Transaccion) String() string {
-	return fmt.Sprintf("%s,%s,%d,%s", t.Tipo, t.IDProducto, t.Cantidad, t.Fecha)
-}
+// Producto y Transaccion son alias del dominio compartido en el paquete
+// inventario, para que este archivo no tenga que tocarse cuando cambie el
+// backend de almacenamiento.
+type Producto = inventario.Producto
+type Transaccion = inventario.Transaccion
 
 // --- Funciones requeridas ---
 
-// leerInventario lee el inventario desde un CSV y devuelve slice de Producto
-func leerInventario(nombreArchivo string) ([]Producto, error) {
-	f, err := os.Open(nombreArchivo)
-	if err != nil {
-		return nil, fmt.Errorf("no se pudo abrir inventario: %w", err)
-	}
-	defer f.Close()
+// leerInventario lee el inventario desde un CSV y devuelve slice de Producto.
+// Internamente consume StreamInventario fila a fila (sin ReadAll), con las
+// mismas CSVOption; esta función existe para quien solo quiera el resultado
+// completo en memoria. csvrepo.LoadProductos lee el inventario CSV por su
+// cuenta, sin pasar por aquí.
+func leerInventario(nombreArchivo string, opts ...CSVOption) ([]Producto, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	reader := csv.NewReader(f)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("error leyendo CSV inventario: %w", err)
-	}
+	productosCh, errsCh := StreamInventario(ctx, nombreArchivo, opts...)
 
 	var productos []Producto
-	for i, rec := range records {
-		// saltar cabecera
-		if i == 0 {
-			continue
-		}
-		if len(rec) < 5 {
-			continue
-		}
-		precio, err := strconv.ParseFloat(rec[3], 64)
-		if err != nil {
-			return nil, fmt.Errorf("precio inválido en línea %d: %w", i+1, err)
-		}
-		stock, err := strconv.Atoi(rec[4])
-		if err != nil {
-			return nil, fmt.Errorf("stock inválido en línea %d: %w", i+1, err)
-		}
-		p := Producto{
-			ID:        rec[0],
-			Nombre:    rec[1],
-			Categoria: rec[2],
-			Precio:    precio,
-			Stock:     stock,
-		}
+	for p := range productosCh {
 		productos = append(productos, p)
 	}
+	if err := <-errsCh; err != nil {
+		return nil, err
+	}
 	return productos, nil
 }
 
-// leerTransacciones lee las transacciones desde CSV y devuelve slice de Transaccion
-func leerTransacciones(nombreArchivo string) ([]Transaccion, error) {
-	f, err := os.Open(nombreArchivo)
-	if err != nil {
-		return nil, fmt.Errorf("no se pudo abrir transacciones: %w", err)
-	}
-	defer f.Close()
+// leerTransacciones lee las transacciones desde CSV y devuelve slice de
+// Transaccion, apoyándose en StreamTransacciones por la misma razón que
+// leerInventario.
+func leerTransacciones(nombreArchivo string, opts ...CSVOption) ([]Transaccion, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	reader := csv.NewReader(f)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("error leyendo CSV transacciones: %w", err)
-	}
+	transCh, errsCh := StreamTransacciones(ctx, nombreArchivo, opts...)
 
 	var trans []Transaccion
-	for i, rec := range records {
-		// saltar cabecera
-		if i == 0 {
-			continue
-		}
-		if len(rec) < 4 {
-			continue
-		}
-		cant, err := strconv.Atoi(rec[2])
-		if err != nil {
-			return nil, fmt.Errorf("cantidad inválida en línea %d: %w", i+1, err)
-		}
-		t := Transaccion{
-			Tipo:       rec[0],
-			IDProducto: rec[1],
-			Cantidad:   cant,
-			Fecha:      rec[3],
-		}
+	for t := range transCh {
 		trans = append(trans, t)
 	}
+	if err := <-errsCh; err != nil {
+		return nil, err
+	}
 	return trans, nil
 }
 
+// ErrorProceso es un error de aplicar una transacción, con Producto y Tipo
+// como campos propios en vez de enterrados en Msg: es lo que necesita el
+// Logger con formato JSON para que una herramienta externa pueda filtrar por
+// producto sin tener que parsear texto libre.
+type ErrorProceso struct {
+	Producto string
+	Tipo     string
+	Msg      string
+}
+
 // procesarTransacciones procesa las transacciones sobre los productos.
-// Devuelve una lista de mensajes de error (strings) que no pudieron aplicarse.
-func procesarTransacciones(productos []Producto, transacciones []Transaccion) []string {
-	var errores []string
+// Devuelve una lista de errores que no pudieron aplicarse.
+func procesarTransacciones(productos []Producto, transacciones []Transaccion) []ErrorProceso {
+	var errores []ErrorProceso
 
 	// índice rápido por ID (posición en slice)
 	index := make(map[string]int)
@@ -130,15 +86,23 @@ func procesarTransacciones(productos []Producto, transacciones []Transaccion) []
 	for _, t := range transacciones {
 		pos, existe := index[t.IDProducto]
 		if !existe {
-			errores = append(errores, fmt.Sprintf("ERROR: Producto %s no encontrado en transacción de tipo %s (fecha: %s)", t.IDProducto, t.Tipo, t.Fecha))
+			errores = append(errores, ErrorProceso{
+				Producto: t.IDProducto,
+				Tipo:     t.Tipo,
+				Msg:      fmt.Sprintf("ERROR: Producto %s no encontrado en transacción de tipo %s (fecha: %s)", t.IDProducto, t.Tipo, t.Fecha),
+			})
 			continue
 		}
 
 		switch t.Tipo {
 		case "VENTA":
 			if productos[pos].Stock < t.Cantidad {
-				errores = append(errores, fmt.Sprintf("ERROR: Stock insuficiente para venta. Producto: %s, Stock actual: %d, Cantidad solicitada: %d (fecha: %s)",
-					productos[pos].ID, productos[pos].Stock, t.Cantidad, t.Fecha))
+				errores = append(errores, ErrorProceso{
+					Producto: productos[pos].ID,
+					Tipo:     t.Tipo,
+					Msg: fmt.Sprintf("ERROR: Stock insuficiente para venta. Producto: %s, Stock actual: %d, Cantidad solicitada: %d (fecha: %s)",
+						productos[pos].ID, productos[pos].Stock, t.Cantidad, t.Fecha),
+				})
 				continue
 			}
 			productos[pos].Stock -= t.Cantidad
@@ -147,7 +111,11 @@ func procesarTransacciones(productos []Producto, transacciones []Transaccion) []
 		case "DEVOLUCION":
 			productos[pos].Stock += t.Cantidad
 		default:
-			errores = append(errores, fmt.Sprintf("ERROR: Tipo de transacción desconocido '%s' para producto %s (fecha: %s)", t.Tipo, t.IDProducto, t.Fecha))
+			errores = append(errores, ErrorProceso{
+				Producto: t.IDProducto,
+				Tipo:     t.Tipo,
+				Msg:      fmt.Sprintf("ERROR: Tipo de transacción desconocido '%s' para producto %s (fecha: %s)", t.Tipo, t.IDProducto, t.Fecha),
+			})
 		}
 	}
 
@@ -156,6 +124,41 @@ func procesarTransacciones(productos []Producto, transacciones []Transaccion) []
 	return errores
 }
 
+// procesarTransaccionesPipeline es la variante pensada para archivos grandes:
+// lee el archivo de transacciones en streaming con StreamTransacciones y lo
+// reparte entre 'workers' goroutinas con ProcessStreamFanOut, de modo que nunca
+// se mantiene en memoria más que el lote de transacciones en vuelo. 'productos'
+// se consulta por ID en vez de por posición, así que se indexa una sola vez.
+// dedupe puede ser nil; si no lo es, las transacciones ya aplicadas en una
+// ejecución anterior se descartan en vez de aplicarse otra vez.
+func procesarTransaccionesPipeline(ctx context.Context, productos []Producto, nombreTransacciones string, workers int, dedupe *DedupeCache, opts ...CSVOption) []ErrorProceso {
+	index := make(map[string]*Producto, len(productos))
+	for i := range productos {
+		index[productos[i].ID] = &productos[i]
+	}
+
+	transCh, readErrs := StreamTransacciones(ctx, nombreTransacciones, opts...)
+
+	errsCh := make(chan ErrorProceso)
+	var errores []ErrorProceso
+	done := make(chan struct{})
+	go func() {
+		for e := range errsCh {
+			errores = append(errores, e)
+		}
+		close(done)
+	}()
+
+	ProcessStreamFanOut(index, workers, transCh, errsCh, dedupe)
+	close(errsCh)
+	<-done
+
+	if err := <-readErrs; err != nil {
+		errores = append(errores, ErrorProceso{Msg: fmt.Sprintf("ERROR: %v", err)})
+	}
+	return errores
+}
+
 // escribirInventario escribe el inventario en formato CSV al archivo indicado.
 func escribirInventario(productos []Producto, nombreArchivo string) error {
 	f, err := os.Create(nombreArchivo)
@@ -164,10 +167,17 @@ func escribirInventario(productos []Producto, nombreArchivo string) error {
 	}
 	defer f.Close()
 
+	return escribirInventarioEn(f, productos)
+}
+
+// escribirInventarioEn escribe el CSV de inventario en un *os.File ya abierto,
+// sin decidir cómo se creó ni cuándo se cierra: la usan tanto escribirInventario
+// como walFiler.Commit, que necesita escribir en un archivo temporal antes de
+// renombrarlo sobre el definitivo.
+func escribirInventarioEn(f *os.File, productos []Producto) error {
 	writer := csv.NewWriter(f)
 	defer writer.Flush()
 
-	// cabecera
 	if err := writer.Write([]string{"ID", "Nombre", "Categoría", "Precio", "Stock"}); err != nil {
 		return fmt.Errorf("error escribiendo cabecera: %w", err)
 	}
@@ -184,12 +194,13 @@ func escribirInventario(productos []Producto, nombreArchivo string) error {
 			return fmt.Errorf("error escribiendo registro: %w", err)
 		}
 	}
-	return nil
+	return writer.Error()
 }
 
-// generarReporteBajoStock escribe un archivo con los productos cuyo stock < limite.
-func generarReporteBajoStock(productos []Producto, limite int) error {
-	nombre := "productos_bajo_stock.txt"
+// generarReporteBajoStock escribe, dentro de outputDir, un archivo con los
+// productos cuyo stock < limite.
+func generarReporteBajoStock(productos []Producto, limite int, outputDir string) error {
+	nombre := filepath.Join(outputDir, "productos_bajo_stock.txt")
 	f, err := os.Create(nombre)
 	if err != nil {
 		return fmt.Errorf("no se pudo crear reporte bajo stock: %w", err)
@@ -211,75 +222,146 @@ func generarReporteBajoStock(productos []Producto, limite int) error {
 	return nil
 }
 
-// escribirLog escribe los errores en un archivo de log usando log.SetOutput y también devuelve error si falla
-func escribirLog(errores []string, nombreArchivo string) error {
+// escribirLog registra los errores de procesamiento con el Logger con
+// niveles/rotación, en vez de redirigir el paquete log global como antes.
+// Cada ErrorProceso aporta su Producto/Tipo reales, así que en formato JSON
+// una herramienta externa puede filtrar por esos campos en vez de tener que
+// grepear el mensaje libre.
+func escribirLog(errores []ErrorProceso, nombreArchivo string, verbose bool, formato string, maxBytes int64) error {
 	// asegurarnos de crear directorio si hace falta
 	dir := filepath.Dir(nombreArchivo)
 	if dir != "." {
 		_ = os.MkdirAll(dir, 0755)
 	}
 
-	f, err := os.Create(nombreArchivo)
+	logger, err := NewLogger(nombreArchivo, verbose, formato, maxBytes)
 	if err != nil {
-		return fmt.Errorf("no se pudo crear archivo de log: %w", err)
+		return err
 	}
-	defer f.Close()
-
-	// redirigimos log a ese fichero
-	log.SetOutput(f)
-	log.SetFlags(log.LstdFlags) // incluir timestamp
+	defer logger.Close()
 
 	for _, e := range errores {
-		// añadimos prefijo ERROR para coherencia con enunciado
-		log.Println("[ERROR]:", e)
+		producto, tipo := e.Producto, e.Tipo
+		if producto == "" {
+			producto = "-"
+		}
+		if tipo == "" {
+			tipo = "-"
+		}
+		logger.Error(producto, tipo, e.Msg)
 	}
 
 	return nil
 }
 
-// --- main: orquestador ---
-func main() {
-	invFile := "inventario.txt"
-	transFile := "transacciones.txt"
+// abrirRepositorio construye el inventario.Repository correspondiente al
+// backend elegido. "csv" sigue usando los archivos planos de siempre; "sqlite"
+// guarda en una base SQLite, lo que permite actualizaciones incrementales sin
+// reescribir todo el inventario en cada ejecución. Si la base todavía no
+// tiene productos, se siembra desde invFile para que "--backend=sqlite" sea
+// utilizable desde el primer arranque en vez de empezar con el inventario
+// vacío.
+func abrirRepositorio(backend, invFile, transFile, sqlitePath string) (inventario.Repository, func(), error) {
+	switch backend {
+	case "csv":
+		return csvrepo.New(invFile, transFile), func() {}, nil
+	case "sqlite":
+		repo, err := sqlrepo.Open(sqlitePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := sembrarSQLiteDesdeCSV(repo, invFile); err != nil {
+			repo.Close()
+			return nil, nil, err
+		}
+		return repo, func() { repo.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("backend desconocido: %q (usa csv o sqlite)", backend)
+	}
+}
 
-	// 1) leer inventario
-	productos, err := leerInventario(invFile)
+// sembrarSQLiteDesdeCSV importa invFile a la base SQLite la primera vez que
+// se usa (es decir, mientras no tenga productos todavía). Sin este paso,
+// "--backend=sqlite" arrancaba siempre con la base vacía aunque --inventory
+// apuntara a un CSV con datos reales, y toda transacción fallaba con
+// "producto no encontrado".
+func sembrarSQLiteDesdeCSV(repo *sqlrepo.Repository, invFile string) error {
+	existentes, err := repo.LoadProductos()
 	if err != nil {
-		fmt.Println("Fallo leyendo inventario:", err)
-		return
+		return fmt.Errorf("fallo comprobando si la base SQLite ya tiene productos: %w", err)
+	}
+	if len(existentes) > 0 {
+		return nil
+	}
+	if _, err := os.Stat(invFile); os.IsNotExist(err) {
+		return nil
 	}
 
-	// 2) leer transacciones
-	transacciones, err := leerTransacciones(transFile)
+	productos, err := csvrepo.New(invFile, "").LoadProductos()
 	if err != nil {
-		fmt.Println("Fallo leyendo transacciones:", err)
-		return
+		return fmt.Errorf("fallo leyendo %s para sembrar la base SQLite: %w", invFile, err)
 	}
+	if len(productos) == 0 {
+		return nil
+	}
+	if err := repo.SaveProductos(productos); err != nil {
+		return fmt.Errorf("fallo sembrando la base SQLite desde %s: %w", invFile, err)
+	}
+	return nil
+}
 
-	// 3) procesar transacciones
-	errores := procesarTransacciones(productos, transacciones)
-
-	// 4) escribir inventario actualizado
-	if err := escribirInventario(productos, "inventario_actualizado.txt"); err != nil {
-		fmt.Println("Error escribiendo inventario actualizado:", err)
-		return
+// --- main: despacha al subcomando pedido ---
+func main() {
+	if len(os.Args) < 2 {
+		imprimirUso()
+		os.Exit(1)
 	}
 
-	// 5) generar reporte bajo stock (limite 10)
-	if err := generarReporteBajoStock(productos, 10); err != nil {
-		fmt.Println("Error generando reporte bajo stock:", err)
+	var err error
+	switch os.Args[1] {
+	case "process":
+		err = cmdProcess(os.Args[2:])
+	case "report":
+		err = cmdReport(os.Args[2:])
+	case "validate":
+		err = cmdValidate(os.Args[2:])
+	case "stats":
+		err = cmdStats(os.Args[2:])
+	case "dedupe":
+		err = despacharDedupe(os.Args[2:])
+	case "server":
+		err = cmdServer(os.Args[2:])
+	case "client":
+		err = cmdClient(os.Args[2:])
+	case "-h", "--help", "help":
+		imprimirUso()
 		return
+	default:
+		fmt.Printf("subcomando desconocido: %q\n\n", os.Args[1])
+		imprimirUso()
+		os.Exit(1)
 	}
 
-	// 6) escribir log de errores
-	if err := escribirLog(errores, "errores.log"); err != nil {
-		fmt.Println("Error escribiendo log de errores:", err)
-		return
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
+}
+
+func imprimirUso() {
+	fmt.Println("uso: psp <process|report|validate|stats|dedupe|server|client> [opciones]")
+	fmt.Println("process/report/validate/stats aceptan --inventory, --transactions, --output-dir,")
+	fmt.Println("--low-stock-threshold, --delimiter, --lazy-quotes y --fields-per-record")
+	fmt.Println("psp dedupe stats muestra el estado del caché de transacciones aplicadas")
+	fmt.Println("psp server expone el inventario por HTTP (--addr, por defecto :8080)")
+	fmt.Println("psp client <productos|producto|vender|comprar|devolver|bajo-stock> [--addr url] [args] habla con psp server")
+}
 
-	// resumen por consola
-	fmt.Println("Proceso completado.")
-	fmt.Println("Inventario actualizado -> inventario_actualizado.txt")
-	fmt.Println("Reporte bajo stock -> productos_bajo_stock.txt")
-	fmt.Println("Errores (si los hay) -> errores.log")
+// despacharDedupe resuelve "psp dedupe <subcomando>"; por ahora el único es
+// "stats".
+func despacharDedupe(args []string) error {
+	if len(args) == 0 || args[0] != "stats" {
+		return fmt.Errorf("uso: psp dedupe stats [opciones]")
+	}
+	return cmdDedupeStats(args[1:])
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/25xabierif/PSP/inventario/sqlrepo"
+)
+
+// TestSembrarSQLiteDesdeCSVSoloSiVacia comprueba el caso que dejaba
+// "--backend=sqlite" inutilizable: una base recién creada se siembra desde
+// el CSV de --inventory, pero si ya tiene productos (ejecuciones
+// posteriores) no se vuelve a sembrar y se respeta lo que haya en la base.
+func TestSembrarSQLiteDesdeCSVSoloSiVacia(t *testing.T) {
+	dir := t.TempDir()
+	invPath := filepath.Join(dir, "inventario.txt")
+	if err := escribirInventario([]Producto{{ID: "P1", Nombre: "Tornillo", Categoria: "Ferretería", Precio: 0.5, Stock: 10}}, invPath); err != nil {
+		t.Fatalf("escribirInventario: %v", err)
+	}
+
+	repo, err := sqlrepo.Open(filepath.Join(dir, "inventario.db"))
+	if err != nil {
+		t.Fatalf("sqlrepo.Open: %v", err)
+	}
+	defer repo.Close()
+
+	if err := sembrarSQLiteDesdeCSV(repo, invPath); err != nil {
+		t.Fatalf("sembrarSQLiteDesdeCSV: %v", err)
+	}
+	productos, err := repo.LoadProductos()
+	if err != nil {
+		t.Fatalf("LoadProductos: %v", err)
+	}
+	if len(productos) != 1 || productos[0].Stock != 10 {
+		t.Fatalf("tras sembrar, LoadProductos = %+v, quería P1 con Stock=10", productos)
+	}
+
+	// Una segunda siembra, con la base ya poblada y el CSV sin tocar, no
+	// debe pisar cambios hechos directamente en la base.
+	productos[0].Stock = 3
+	if err := repo.SaveProductos(productos); err != nil {
+		t.Fatalf("SaveProductos: %v", err)
+	}
+	if err := sembrarSQLiteDesdeCSV(repo, invPath); err != nil {
+		t.Fatalf("segunda sembrarSQLiteDesdeCSV: %v", err)
+	}
+	productos, err = repo.LoadProductos()
+	if err != nil {
+		t.Fatalf("LoadProductos tras segunda siembra: %v", err)
+	}
+	if len(productos) != 1 || productos[0].Stock != 3 {
+		t.Fatalf("la base no debía resembrarse con la base ya poblada, quedó %+v", productos)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// server expone el inventario en memoria por HTTP. El ticket original pedía
+// también un servicio gRPC, un spec OpenAPI y un subcomando "psp client".
+// De esos tres, openapi.yaml (escrito a mano, no generado: no hay un
+// generador de terceros disponible) y cmdClient en client.go sí se entregan
+// en esta vuelta. gRPC sigue fuera de alcance: exige
+// google.golang.org/grpc más protoc, y ninguno de los dos está en el
+// caché local de módulos ni accesible sin red, así que no hay forma
+// honesta de añadirlo desde este entorno.
+type server struct {
+	mu        sync.RWMutex
+	productos map[string]*Producto
+	repo      interface {
+		SaveProductos([]Producto) error
+	}
+}
+
+func newServer(productos []Producto, repo interface{ SaveProductos([]Producto) error }) *server {
+	index := make(map[string]*Producto, len(productos))
+	for i := range productos {
+		index[productos[i].ID] = &productos[i]
+	}
+	return &server{productos: index, repo: repo}
+}
+
+func (s *server) snapshot() []Producto {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	lista := make([]Producto, 0, len(s.productos))
+	for _, p := range s.productos {
+		lista = append(lista, *p)
+	}
+	return lista
+}
+
+func (s *server) flush() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	lista := make([]Producto, 0, len(s.productos))
+	for _, p := range s.productos {
+		lista = append(lista, *p)
+	}
+	return s.repo.SaveProductos(lista)
+}
+
+func (s *server) handleProductos(w http.ResponseWriter, r *http.Request) {
+	resto := strings.TrimPrefix(r.URL.Path, "/productos")
+	resto = strings.Trim(resto, "/")
+
+	if resto == "" {
+		json.NewEncoder(w).Encode(s.snapshot())
+		return
+	}
+
+	s.mu.RLock()
+	p, existe := s.productos[resto]
+	var copia Producto
+	if existe {
+		copia = *p
+	}
+	s.mu.RUnlock()
+
+	if !existe {
+		http.Error(w, fmt.Sprintf("producto %q no encontrado", resto), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(copia)
+}
+
+func (s *server) handleTransacciones(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "solo POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var t Transaccion
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, fmt.Sprintf("cuerpo inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	p, existe := s.productos[t.IDProducto]
+	var aplicado bool
+	var mensaje string
+	if !existe {
+		mensaje = fmt.Sprintf("producto %s no encontrado", t.IDProducto)
+	} else {
+		switch t.Tipo {
+		case "VENTA":
+			if p.Stock < t.Cantidad {
+				mensaje = "stock insuficiente"
+			} else {
+				p.Stock -= t.Cantidad
+				aplicado = true
+			}
+		case "COMPRA", "DEVOLUCION":
+			p.Stock += t.Cantidad
+			aplicado = true
+		default:
+			mensaje = fmt.Sprintf("tipo de transacción desconocido: %s", t.Tipo)
+		}
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Aplicado bool   `json:"aplicado"`
+		Mensaje  string `json:"mensaje,omitempty"`
+	}{Aplicado: aplicado, Mensaje: mensaje})
+}
+
+func (s *server) handleReporteBajoStock(w http.ResponseWriter, r *http.Request) {
+	limite := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			limite = n
+		}
+	}
+
+	var bajoStock []Producto
+	for _, p := range s.snapshot() {
+		if p.Stock < limite {
+			bajoStock = append(bajoStock, p)
+		}
+	}
+	json.NewEncoder(w).Encode(bajoStock)
+}
+
+// cmdServer arranca "psp server": sirve el inventario por HTTP hasta que
+// llega SIGTERM/SIGINT, momento en el que vuelca el estado en memoria al
+// backend antes de salir.
+func cmdServer(args []string) error {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	inventory := fs.String("inventory", "inventario.txt", "ruta del inventario CSV")
+	backend := fs.String("backend", "csv", "backend de almacenamiento: csv o sqlite")
+	sqlitePath := fs.String("sqlite-path", "inventario.db", "ruta de la base SQLite cuando --backend=sqlite")
+	addr := fs.String("addr", ":8080", "dirección donde escuchar")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	repo, cerrar, err := abrirRepositorio(*backend, *inventory, "", *sqlitePath)
+	if err != nil {
+		return fmt.Errorf("fallo preparando backend: %w", err)
+	}
+	defer cerrar()
+
+	productos, err := repo.LoadProductos()
+	if err != nil {
+		return fmt.Errorf("fallo leyendo inventario: %w", err)
+	}
+
+	srv := newServer(productos, repo)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/productos", srv.handleProductos)
+	mux.HandleFunc("/productos/", srv.handleProductos)
+	mux.HandleFunc("/transacciones", srv.handleTransacciones)
+	mux.HandleFunc("/reportes/bajo-stock", srv.handleReporteBajoStock)
+
+	httpServer := &http.Server{Addr: *addr, Handler: mux}
+
+	idleClosed := make(chan struct{})
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+		<-sigs
+
+		// Dejar de aceptar conexiones y esperar a que las peticiones en
+		// vuelo terminen (por ejemplo, un POST /transacciones ya aceptado)
+		// antes de volcar el inventario, para no perder la última
+		// transacción aplicada justo antes del cierre.
+		httpServer.Shutdown(context.Background())
+		if err := srv.flush(); err != nil {
+			fmt.Println("error guardando inventario al cerrar:", err)
+		}
+		close(idleClosed)
+	}()
+
+	fmt.Println("psp server escuchando en", *addr)
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	<-idleClosed
+	return nil
+}
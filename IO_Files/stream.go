@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// csvConfig recoge las opciones de lectura que el CLI expone como banderas
+// (--delimiter, --lazy-quotes, --fields-per-record).
+type csvConfig struct {
+	delimiter       rune
+	lazyQuotes      bool
+	fieldsPerRecord int
+}
+
+// CSVOption ajusta el csv.Reader que usan los Stream*; por defecto (sin
+// opciones) el comportamiento es el mismo de siempre: coma como separador y
+// el número de campos fijado por la primera fila.
+type CSVOption func(*csvConfig)
+
+// WithDelimiter cambia el separador de campos (csv.Reader.Comma).
+func WithDelimiter(d rune) CSVOption { return func(c *csvConfig) { c.delimiter = d } }
+
+// WithLazyQuotes activa csv.Reader.LazyQuotes, para tolerar comillas sueltas.
+func WithLazyQuotes(b bool) CSVOption { return func(c *csvConfig) { c.lazyQuotes = b } }
+
+// WithFieldsPerRecord fija csv.Reader.FieldsPerRecord; -1 tolera filas con un
+// número de campos distinto entre sí (CSVs "ragged").
+func WithFieldsPerRecord(n int) CSVOption { return func(c *csvConfig) { c.fieldsPerRecord = n } }
+
+func newCSVReader(r io.Reader, opts ...CSVOption) *csv.Reader {
+	cfg := csvConfig{delimiter: ','}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	reader := csv.NewReader(r)
+	reader.Comma = cfg.delimiter
+	reader.LazyQuotes = cfg.lazyQuotes
+	reader.FieldsPerRecord = cfg.fieldsPerRecord
+	return reader
+}
+
+// abrirLectura abre nombreArchivo, salvo que sea "-", en cuyo caso lee de
+// stdin; así los Stream* pueden recibir datos por una tubería.
+func abrirLectura(nombreArchivo string) (io.ReadCloser, error) {
+	if nombreArchivo == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(nombreArchivo)
+}
+
+// StreamInventario lee el inventario fila a fila (sin ReadAll) y lo emite por un
+// canal, de forma que archivos grandes no necesitan cargarse enteros en memoria.
+// El canal de productos se cierra al terminar; el de errores recibe como mucho
+// un valor antes de cerrarse. Cancelar ctx detiene la lectura.
+func StreamInventario(ctx context.Context, nombreArchivo string, opts ...CSVOption) (<-chan Producto, <-chan error) {
+	out := make(chan Producto)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(errs)
+
+		f, err := abrirLectura(nombreArchivo)
+		if err != nil {
+			errs <- fmt.Errorf("no se pudo abrir inventario: %w", err)
+			return
+		}
+		defer f.Close()
+
+		reader := newCSVReader(f, opts...)
+		linea := 0
+		for {
+			rec, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				errs <- fmt.Errorf("error leyendo CSV inventario: %w", err)
+				return
+			}
+			linea++
+			if linea == 1 {
+				// saltar cabecera
+				continue
+			}
+			if len(rec) < 5 {
+				continue
+			}
+			precio, err := strconv.ParseFloat(rec[3], 64)
+			if err != nil {
+				errs <- fmt.Errorf("precio inválido en línea %d: %w", linea, err)
+				return
+			}
+			stock, err := strconv.Atoi(rec[4])
+			if err != nil {
+				errs <- fmt.Errorf("stock inválido en línea %d: %w", linea, err)
+				return
+			}
+			p := Producto{
+				ID:        rec[0],
+				Nombre:    rec[1],
+				Categoria: rec[2],
+				Precio:    precio,
+				Stock:     stock,
+			}
+			select {
+			case out <- p:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return out, errs
+}
+
+// StreamTransacciones lee las transacciones fila a fila y las emite por un canal,
+// igual que StreamInventario pero para Transaccion. nombreArchivo puede ser
+// "-" para leer de stdin.
+func StreamTransacciones(ctx context.Context, nombreArchivo string, opts ...CSVOption) (<-chan Transaccion, <-chan error) {
+	out := make(chan Transaccion)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(errs)
+
+		f, err := abrirLectura(nombreArchivo)
+		if err != nil {
+			errs <- fmt.Errorf("no se pudo abrir transacciones: %w", err)
+			return
+		}
+		defer f.Close()
+
+		reader := newCSVReader(f, opts...)
+		linea := 0
+		for {
+			rec, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				errs <- fmt.Errorf("error leyendo CSV transacciones: %w", err)
+				return
+			}
+			linea++
+			if linea == 1 {
+				continue
+			}
+			if len(rec) < 4 {
+				continue
+			}
+			cant, err := strconv.Atoi(rec[2])
+			if err != nil {
+				errs <- fmt.Errorf("cantidad inválida en línea %d: %w", linea, err)
+				return
+			}
+			t := Transaccion{
+				Tipo:       rec[0],
+				IDProducto: rec[1],
+				Cantidad:   cant,
+				Fecha:      rec[3],
+			}
+			if len(rec) >= 5 && rec[4] != "" {
+				t.ID = rec[4]
+			}
+			select {
+			case out <- t:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return out, errs
+}
+
+// shardFor enruta un IDProducto al worker que lo procesa, por hash. Repartir
+// solo el *lock* por shard (como hacía antes esta función) no basta para
+// preservar el orden: con N workers leyendo del mismo canal, quién gana
+// mu.Lock() para un producto dado depende del scheduler, no del orden en que
+// las transacciones salieron del CSV, así que "VENTA 5" seguida de "VENTA 3"
+// sobre el mismo producto podía aplicarse en cualquier orden. Ahora cada
+// producto va siempre al mismo worker, así que las transacciones que lo
+// afectan se procesan en el orden en que se leyeron.
+func shardFor(id string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+	return int(h.Sum32() % uint32(n))
+}
+
+// ProcessStream aplica, en orden, las transacciones que le llegan por 'in'
+// sobre el mapa 'productos' (indexado por ID). No toma ningún lock: está
+// pensada para ser la única goroutine que procesa su shard, de modo que cada
+// Producto solo lo toca un worker (ver ProcessStreamFanOut). Los mensajes de
+// error se envían a 'errs' en vez de acumularse en un slice, así el llamador
+// puede drenarlos en paralelo a la escritura. Si dedupe no es nil, las
+// transacciones ya aplicadas con éxito en una ejecución anterior se
+// descartan en silencio en vez de aplicarse otra vez; una transacción que
+// falla (producto inexistente, stock insuficiente...) no se marca, para que
+// un reproceso posterior vuelva a intentarla.
+func ProcessStream(productos map[string]*Producto, in <-chan Transaccion, errs chan<- ErrorProceso, dedupe *DedupeCache) {
+	for t := range in {
+		id := transaccionID(t)
+		if dedupe != nil && dedupe.AlreadyApplied(id) {
+			continue
+		}
+
+		p, existe := productos[t.IDProducto]
+		if !existe {
+			errs <- ErrorProceso{
+				Producto: t.IDProducto,
+				Tipo:     t.Tipo,
+				Msg:      fmt.Sprintf("ERROR: Producto %s no encontrado en transacción de tipo %s (fecha: %s)", t.IDProducto, t.Tipo, t.Fecha),
+			}
+			continue
+		}
+
+		aplicado := true
+		switch t.Tipo {
+		case "VENTA":
+			if p.Stock < t.Cantidad {
+				errs <- ErrorProceso{
+					Producto: p.ID,
+					Tipo:     t.Tipo,
+					Msg: fmt.Sprintf("ERROR: Stock insuficiente para venta. Producto: %s, Stock actual: %d, Cantidad solicitada: %d (fecha: %s)",
+						p.ID, p.Stock, t.Cantidad, t.Fecha),
+				}
+				aplicado = false
+			} else {
+				p.Stock -= t.Cantidad
+			}
+		case "COMPRA":
+			p.Stock += t.Cantidad
+		case "DEVOLUCION":
+			p.Stock += t.Cantidad
+		default:
+			errs <- ErrorProceso{
+				Producto: t.IDProducto,
+				Tipo:     t.Tipo,
+				Msg:      fmt.Sprintf("ERROR: Tipo de transacción desconocido '%s' para producto %s (fecha: %s)", t.Tipo, t.IDProducto, t.Fecha),
+			}
+			aplicado = false
+		}
+
+		if aplicado && dedupe != nil {
+			dedupe.Mark(id)
+		}
+	}
+}
+
+// ProcessStreamFanOut reparte 'in' entre n workers, pero por shard de
+// IDProducto en vez de repartir round-robin: un distribuidor lee 'in' y
+// enruta cada transacción al canal del worker que le corresponde según
+// shardFor, así que todas las transacciones del mismo producto las procesa
+// siempre la misma goroutina, en el orden en que llegaron. Espera a que 'in'
+// se agote y los n workers terminen antes de volver.
+func ProcessStreamFanOut(productos map[string]*Producto, n int, in <-chan Transaccion, errs chan<- ErrorProceso, dedupe *DedupeCache) {
+	if n < 1 {
+		n = 1
+	}
+
+	canales := make([]chan Transaccion, n)
+	for i := range canales {
+		canales[i] = make(chan Transaccion)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(ch <-chan Transaccion) {
+			defer wg.Done()
+			ProcessStream(productos, ch, errs, dedupe)
+		}(canales[i])
+	}
+
+	for t := range in {
+		canales[shardFor(t.IDProducto, n)] <- t
+	}
+	for _, ch := range canales {
+		close(ch)
+	}
+	wg.Wait()
+}
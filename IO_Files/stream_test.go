@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+// TestProcessStreamFanOutPreservaOrdenPorProducto reproduce el escenario que
+// rompía el reparto por lock: dos VENTAs seguidas sobre el mismo producto
+// deben aplicarse en el orden en que se leyeron, sin importar cuántos
+// workers haya ni el scheduler. Se repite muchas veces porque una carrera
+// no siempre se manifiesta en la primera ejecución.
+func TestProcessStreamFanOutPreservaOrdenPorProducto(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		productos := map[string]*Producto{
+			"P1": {ID: "P1", Stock: 5},
+		}
+		in := make(chan Transaccion)
+		errs := make(chan ErrorProceso, 10)
+
+		done := make(chan struct{})
+		go func() {
+			ProcessStreamFanOut(productos, 8, in, errs, nil)
+			close(done)
+		}()
+
+		in <- Transaccion{Tipo: "VENTA", IDProducto: "P1", Cantidad: 5, Fecha: "2026-01-01"}
+		in <- Transaccion{Tipo: "VENTA", IDProducto: "P1", Cantidad: 3, Fecha: "2026-01-02"}
+		close(in)
+		<-done
+		close(errs)
+
+		// Si "VENTA 5" se aplica primero, deja stock 0 y "VENTA 3" falla por
+		// falta de stock (el único resultado correcto dado el orden de
+		// entrada). Si llegaran desordenadas, "VENTA 3" aplicaría primero y
+		// "VENTA 5" fallaría, dejando stock 2 en vez de 0.
+		if productos["P1"].Stock != 0 {
+			t.Fatalf("iteración %d: stock final = %d, quería 0 (VENTA 5 debía aplicarse antes que VENTA 3)", i, productos["P1"].Stock)
+		}
+
+		var mensajes []ErrorProceso
+		for e := range errs {
+			mensajes = append(mensajes, e)
+		}
+		if len(mensajes) != 1 {
+			t.Fatalf("iteración %d: esperaba 1 error (VENTA 3 rechazada por falta de stock), hubo %d: %v", i, len(mensajes), mensajes)
+		}
+		if mensajes[0].Producto != "P1" || mensajes[0].Tipo != "VENTA" {
+			t.Fatalf("iteración %d: ErrorProceso = %+v, quería Producto=P1 Tipo=VENTA", i, mensajes[0])
+		}
+	}
+}
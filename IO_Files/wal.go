@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// walFiler envuelve el archivo de inventario junto con un sidecar ".wal":
+// cada Transaccion se anota ahí, con número de secuencia y checksum, antes de
+// tocar el inventario en memoria. Así, si el proceso muere entre aplicar una
+// transacción y reescribir el inventario, Recover puede rehacer exactamente
+// lo que faltaba en vez de perderlo o aplicarlo dos veces.
+type walFiler struct {
+	invPath string
+	walPath string
+	seqPath string
+
+	walFile *os.File
+	lastSeq uint64
+}
+
+// openWAL abre (o crea) el WAL de invPath y recupera el último número de
+// secuencia confirmado desde el archivo ".seq" que acompaña al inventario.
+func openWAL(invPath string) (*walFiler, error) {
+	walPath := invPath + ".wal"
+	seqPath := invPath + ".seq"
+
+	f, err := os.OpenFile(walPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir WAL: %w", err)
+	}
+
+	lastSeq, err := leerLastSeq(seqPath)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &walFiler{invPath: invPath, walPath: walPath, seqPath: seqPath, walFile: f, lastSeq: lastSeq}, nil
+}
+
+func leerLastSeq(seqPath string) (uint64, error) {
+	data, err := os.ReadFile(seqPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("no se pudo leer %s: %w", seqPath, err)
+	}
+	seq, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("secuencia inválida en %s: %w", seqPath, err)
+	}
+	return seq, nil
+}
+
+// walChecksum calcula el CRC32 de los campos de la transacción, para detectar
+// una escritura a medias si el proceso muere mientras se anota en el WAL.
+// Incluye t.ID para que una transacción con ID explícito (el único caso en
+// que transaccionID no depende de Tipo|IDProducto|Cantidad|Fecha) lo
+// conserve intacto tras pasar por el WAL.
+func walChecksum(t Transaccion) uint32 {
+	linea := fmt.Sprintf("%s|%s|%s|%d|%s", t.ID, t.Tipo, t.IDProducto, t.Cantidad, t.Fecha)
+	return crc32.ChecksumIEEE([]byte(linea))
+}
+
+// Append anota t en el WAL con el siguiente número de secuencia y hace fsync
+// antes de devolver el control, para que la entrada sea duradera incluso si
+// el proceso muere justo después.
+func (w *walFiler) Append(t Transaccion) (uint64, error) {
+	seq := w.lastSeq + 1
+	linea := fmt.Sprintf("%d|%s|%s|%s|%d|%s|%08x\n", seq, t.ID, t.Tipo, t.IDProducto, t.Cantidad, t.Fecha, walChecksum(t))
+	if _, err := w.walFile.WriteString(linea); err != nil {
+		return 0, fmt.Errorf("error anotando en WAL: %w", err)
+	}
+	if err := w.walFile.Sync(); err != nil {
+		return 0, fmt.Errorf("error sincronizando WAL: %w", err)
+	}
+	w.lastSeq = seq
+	return seq, nil
+}
+
+// Commit escribe 'productos' en un archivo temporal, lo sincroniza, lo
+// renombra sobre invPath (rename es atómico en el mismo filesystem) y solo
+// entonces vacía el WAL y actualiza el archivo de secuencia. Si el proceso
+// muere antes del rename, invPath queda intacto y Recover puede rehacer el
+// trabajo desde el WAL.
+func (w *walFiler) Commit(productos []Producto) error {
+	tmp := w.invPath + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("no se pudo crear inventario temporal: %w", err)
+	}
+	if err := escribirInventarioEn(f, productos); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("error sincronizando inventario temporal: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error cerrando inventario temporal: %w", err)
+	}
+	if err := os.Rename(tmp, w.invPath); err != nil {
+		return fmt.Errorf("error renombrando inventario: %w", err)
+	}
+
+	if err := os.WriteFile(w.seqPath, []byte(strconv.FormatUint(w.lastSeq, 10)), 0644); err != nil {
+		return fmt.Errorf("error actualizando %s: %w", w.seqPath, err)
+	}
+	if err := w.walFile.Truncate(0); err != nil {
+		return fmt.Errorf("error vaciando WAL: %w", err)
+	}
+	if _, err := w.walFile.Seek(0, 0); err != nil {
+		return fmt.Errorf("error rebobinando WAL: %w", err)
+	}
+	return nil
+}
+
+// Close cierra el descriptor del WAL.
+func (w *walFiler) Close() error {
+	return w.walFile.Close()
+}
+
+// leerWALEntradas devuelve las transacciones anotadas en walPath con número
+// de secuencia mayor que lastSeq, en orden, descartando cualquier línea cuyo
+// checksum no cuadre (señal de una escritura a medias antes de un crash).
+func leerWALEntradas(walPath string, lastSeq uint64) ([]Transaccion, error) {
+	f, err := os.Open(walPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir WAL: %w", err)
+	}
+	defer f.Close()
+
+	var pendientes []Transaccion
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		linea := scanner.Text()
+		if linea == "" {
+			continue
+		}
+		campos := strings.Split(linea, "|")
+		if len(campos) != 7 {
+			break // línea a medias, el resto del WAL tampoco es de fiar
+		}
+		seq, err := strconv.ParseUint(campos[0], 10, 64)
+		if err != nil {
+			break
+		}
+		cantidad, err := strconv.Atoi(campos[4])
+		if err != nil {
+			break
+		}
+		t := Transaccion{ID: campos[1], Tipo: campos[2], IDProducto: campos[3], Cantidad: cantidad, Fecha: campos[5]}
+		if fmt.Sprintf("%08x", walChecksum(t)) != campos[6] {
+			break
+		}
+		if seq <= lastSeq {
+			continue
+		}
+		pendientes = append(pendientes, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error leyendo WAL: %w", err)
+	}
+	return pendientes, nil
+}
+
+// Recover repone cualquier transacción del WAL que quedó anotada pero nunca
+// llegó a reflejarse en invPath (por ejemplo, si el proceso murió entre
+// Append y Commit) y deja el inventario y el WAL consistentes. Devuelve los
+// ErrorProceso de las entradas recuperadas que no pudieron reaplicarse (por
+// ejemplo, si el stock ya no alcanza): antes se descartaban en silencio, y un
+// camino de recuperación que traga fallos sin dejar rastro socava la propia
+// auditabilidad que el WAL existe para dar.
+func Recover(invPath, walPath string) ([]ErrorProceso, error) {
+	seqPath := invPath + ".seq"
+	lastSeq, err := leerLastSeq(seqPath)
+	if err != nil {
+		return nil, err
+	}
+
+	productos, err := leerInventario(invPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pendientes, err := leerWALEntradas(walPath, lastSeq)
+	if err != nil {
+		return nil, err
+	}
+	if len(pendientes) == 0 {
+		return nil, nil
+	}
+
+	errores := procesarTransacciones(productos, pendientes)
+	lastSeq += uint64(len(pendientes))
+
+	w := &walFiler{invPath: invPath, walPath: walPath, seqPath: seqPath, lastSeq: lastSeq}
+	f, err := os.OpenFile(walPath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo reabrir WAL para truncarlo: %w", err)
+	}
+	w.walFile = f
+	defer w.walFile.Close()
+
+	if err := w.Commit(productos); err != nil {
+		return nil, err
+	}
+	return errores, nil
+}
+
+// procesarTransaccionesConWAL lee transFile en streaming con
+// StreamTransacciones (en vez de cargarlo entero en un slice con
+// leerTransacciones, que dejaba sin efecto el propósito de "bounded memory"
+// de esta ruta para el backend csv), anota cada transacción en el WAL de
+// invFile antes de aplicarla sobre 'productos', y al terminar hace Commit
+// (rename atómico + vaciado del WAL). Es la ruta que usa el backend csv, en
+// vez de procesarTransaccionesPipeline, porque el WAL necesita un único
+// escritor secuencial para que los números de secuencia sean fiables. dedupe
+// puede ser nil; si no lo es, las transacciones ya aplicadas con éxito antes
+// ni se anotan en el WAL ni se aplican de nuevo, pero una que falla no se
+// marca, para que un reproceso posterior pueda reintentarla.
+func procesarTransaccionesConWAL(invFile, transFile string, productos []Producto, dedupe *DedupeCache, opts ...CSVOption) ([]ErrorProceso, error) {
+	w, err := openWAL(invFile)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	transCh, readErrs := StreamTransacciones(ctx, transFile, opts...)
+
+	var errores []ErrorProceso
+	for t := range transCh {
+		id := transaccionID(t)
+		if dedupe != nil && dedupe.AlreadyApplied(id) {
+			continue
+		}
+		if _, err := w.Append(t); err != nil {
+			return nil, err
+		}
+		fallos := procesarTransacciones(productos, []Transaccion{t})
+		errores = append(errores, fallos...)
+		if dedupe != nil && len(fallos) == 0 {
+			dedupe.Mark(id)
+		}
+	}
+	if err := <-readErrs; err != nil {
+		return nil, err
+	}
+
+	if err := w.Commit(productos); err != nil {
+		return nil, err
+	}
+	return errores, nil
+}
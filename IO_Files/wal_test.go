@@ -0,0 +1,132 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestRecoverRehaceTransaccionTrasCrashEntreAppendYCommit simula el caso que
+// walFiler está pensado para resolver: el proceso anota una transacción en el
+// WAL, pero muere antes de llegar a Commit (el inventario en disco no llega a
+// reescribirse ni el WAL a vaciarse). Recover, al arrancar de nuevo, debe
+// reaplicar esa transacción pendiente y dejar inventario y WAL consistentes.
+func TestRecoverRehaceTransaccionTrasCrashEntreAppendYCommit(t *testing.T) {
+	dir := t.TempDir()
+	invPath := filepath.Join(dir, "inventario.txt")
+
+	inicial := []Producto{{ID: "P1", Nombre: "Tornillo", Categoria: "Ferretería", Precio: 0.5, Stock: 10}}
+	if err := escribirInventario(inicial, invPath); err != nil {
+		t.Fatalf("escribirInventario: %v", err)
+	}
+
+	w, err := openWAL(invPath)
+	if err != nil {
+		t.Fatalf("openWAL: %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+
+	// Anotar la transacción y simular el crash: nunca se llama a w.Commit,
+	// así que invPath y el ".seq" quedan tal cual estaban antes de aplicarla.
+	if _, err := w.Append(Transaccion{Tipo: "VENTA", IDProducto: "P1", Cantidad: 4, Fecha: "2026-01-01"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	errores, err := Recover(invPath, invPath+".wal")
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if len(errores) != 0 {
+		t.Fatalf("Recover no debería reportar errores al reaplicar una venta con stock suficiente, hubo %d: %v", len(errores), errores)
+	}
+
+	productos, err := leerInventario(invPath)
+	if err != nil {
+		t.Fatalf("leerInventario tras Recover: %v", err)
+	}
+	if len(productos) != 1 || productos[0].Stock != 6 {
+		t.Fatalf("stock tras Recover = %+v, quería P1 con Stock=6", productos)
+	}
+
+	pendientes, err := leerWALEntradas(invPath+".wal", mustLastSeq(t, invPath+".seq"))
+	if err != nil {
+		t.Fatalf("leerWALEntradas tras Recover: %v", err)
+	}
+	if len(pendientes) != 0 {
+		t.Fatalf("el WAL debería quedar vacío tras Recover, quedaron %d entradas", len(pendientes))
+	}
+}
+
+// TestRecoverSinWALNoHaceNada comprueba que, si no hubo crash (no existe WAL
+// pendiente), Recover no toca el inventario.
+func TestRecoverSinWALNoHaceNada(t *testing.T) {
+	dir := t.TempDir()
+	invPath := filepath.Join(dir, "inventario.txt")
+
+	inicial := []Producto{{ID: "P1", Nombre: "Tornillo", Categoria: "Ferretería", Precio: 0.5, Stock: 10}}
+	if err := escribirInventario(inicial, invPath); err != nil {
+		t.Fatalf("escribirInventario: %v", err)
+	}
+
+	errores, err := Recover(invPath, invPath+".wal")
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if len(errores) != 0 {
+		t.Fatalf("Recover sin WAL pendiente no debería reportar errores, hubo %d: %v", len(errores), errores)
+	}
+
+	productos, err := leerInventario(invPath)
+	if err != nil {
+		t.Fatalf("leerInventario tras Recover: %v", err)
+	}
+	if len(productos) != 1 || productos[0].Stock != 10 {
+		t.Fatalf("Recover sin WAL pendiente no debería cambiar el stock, quedó %+v", productos)
+	}
+}
+
+// TestRecoverPropagaErroresDeEntradasQueNoPuedenReaplicarse comprueba que una
+// entrada del WAL que ya no puede aplicarse al recuperar (aquí, una venta que
+// dejó el stock por debajo de lo que pide) se reporta en el []ErrorProceso
+// devuelto en vez de perderse en silencio: antes de esta prueba, Recover
+// descartaba procesarTransacciones sin mirarlo.
+func TestRecoverPropagaErroresDeEntradasQueNoPuedenReaplicarse(t *testing.T) {
+	dir := t.TempDir()
+	invPath := filepath.Join(dir, "inventario.txt")
+
+	inicial := []Producto{{ID: "P1", Nombre: "Tornillo", Categoria: "Ferretería", Precio: 0.5, Stock: 2}}
+	if err := escribirInventario(inicial, invPath); err != nil {
+		t.Fatalf("escribirInventario: %v", err)
+	}
+
+	w, err := openWAL(invPath)
+	if err != nil {
+		t.Fatalf("openWAL: %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+
+	// Se anota una venta de 5 unidades con solo 2 en stock: al recuperar,
+	// procesarTransacciones la rechaza por falta de stock.
+	if _, err := w.Append(Transaccion{Tipo: "VENTA", IDProducto: "P1", Cantidad: 5, Fecha: "2026-01-01"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	errores, err := Recover(invPath, invPath+".wal")
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if len(errores) != 1 {
+		t.Fatalf("esperaba 1 ErrorProceso (stock insuficiente), hubo %d: %v", len(errores), errores)
+	}
+	if errores[0].Producto != "P1" || errores[0].Tipo != "VENTA" {
+		t.Fatalf("ErrorProceso = %+v, quería Producto=P1 Tipo=VENTA", errores[0])
+	}
+}
+
+func mustLastSeq(t *testing.T, seqPath string) uint64 {
+	t.Helper()
+	seq, err := leerLastSeq(seqPath)
+	if err != nil {
+		t.Fatalf("leerLastSeq: %v", err)
+	}
+	return seq
+}
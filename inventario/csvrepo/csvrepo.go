@@ -0,0 +1,147 @@
+// Package csvrepo implementa inventario.Repository sobre los archivos CSV
+// planos que ya usaba el programa (inventario.txt / transacciones.txt), para
+// que seguir usando CSV no exija nada especial aparte de "--backend=csv".
+package csvrepo
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/25xabierif/PSP/inventario"
+)
+
+// Repository lee y escribe el inventario en un CSV y añade las transacciones
+// aplicadas a otro CSV, a modo de registro de auditoría.
+type Repository struct {
+	InventarioPath    string
+	TransaccionesPath string
+}
+
+// New crea un Repository que opera sobre los archivos indicados.
+func New(inventarioPath, transaccionesPath string) *Repository {
+	return &Repository{InventarioPath: inventarioPath, TransaccionesPath: transaccionesPath}
+}
+
+// LoadProductos lee el CSV fila a fila con reader.Read() en vez de
+// reader.ReadAll(): ReadAll primero acumula todo el archivo en un
+// [][]string y luego este método lo vuelca en []Producto, así que durante
+// la carga habría dos copias completas del inventario en memoria a la vez.
+// Leyendo fila a fila solo se mantiene la fila actual y el slice de salida.
+func (r *Repository) LoadProductos() ([]inventario.Producto, error) {
+	f, err := os.Open(r.InventarioPath)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir inventario: %w", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+
+	var productos []inventario.Producto
+	linea := 0
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo CSV inventario: %w", err)
+		}
+		linea++
+		if linea == 1 {
+			continue
+		}
+		if len(rec) < 5 {
+			continue
+		}
+		precio, err := strconv.ParseFloat(rec[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("precio inválido en línea %d: %w", linea, err)
+		}
+		stock, err := strconv.Atoi(rec[4])
+		if err != nil {
+			return nil, fmt.Errorf("stock inválido en línea %d: %w", linea, err)
+		}
+		productos = append(productos, inventario.Producto{
+			ID:        rec[0],
+			Nombre:    rec[1],
+			Categoria: rec[2],
+			Precio:    precio,
+			Stock:     stock,
+		})
+	}
+	return productos, nil
+}
+
+func (r *Repository) SaveProductos(productos []inventario.Producto) error {
+	f, err := os.Create(r.InventarioPath)
+	if err != nil {
+		return fmt.Errorf("no se pudo crear archivo inventario: %w", err)
+	}
+	defer f.Close()
+
+	writer := csv.NewWriter(f)
+	defer writer.Flush()
+
+	if err := writer.Write([]string{"ID", "Nombre", "Categoría", "Precio", "Stock"}); err != nil {
+		return fmt.Errorf("error escribiendo cabecera: %w", err)
+	}
+	for _, p := range productos {
+		rec := []string{p.ID, p.Nombre, p.Categoria, strconv.FormatFloat(p.Precio, 'f', 2, 64), strconv.Itoa(p.Stock)}
+		if err := writer.Write(rec); err != nil {
+			return fmt.Errorf("error escribiendo registro: %w", err)
+		}
+	}
+	return nil
+}
+
+// AppendTransaccion añade la transacción al final de TransaccionesPath. Como
+// es un CSV plano, esto no sustituye el historial: solo deja constancia de lo
+// que ya se aplicó.
+func (r *Repository) AppendTransaccion(t inventario.Transaccion) error {
+	nuevo := false
+	if _, err := os.Stat(r.TransaccionesPath); os.IsNotExist(err) {
+		nuevo = true
+	}
+
+	f, err := os.OpenFile(r.TransaccionesPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("no se pudo abrir transacciones: %w", err)
+	}
+	defer f.Close()
+
+	writer := csv.NewWriter(f)
+	defer writer.Flush()
+
+	if nuevo {
+		if err := writer.Write([]string{"Tipo", "IDProducto", "Cantidad", "Fecha"}); err != nil {
+			return fmt.Errorf("error escribiendo cabecera: %w", err)
+		}
+	}
+	rec := []string{t.Tipo, t.IDProducto, strconv.Itoa(t.Cantidad), t.Fecha}
+	if err := writer.Write(rec); err != nil {
+		return fmt.Errorf("error escribiendo transacción: %w", err)
+	}
+	return nil
+}
+
+// QueryBajoStock carga el inventario completo y filtra en memoria, ya que un
+// CSV no soporta consultas: no hay forma de evitar el escaneo completo.
+func (r *Repository) QueryBajoStock(limit int) ([]inventario.Producto, error) {
+	productos, err := r.LoadProductos()
+	if err != nil {
+		return nil, err
+	}
+
+	var bajoStock []inventario.Producto
+	for _, p := range productos {
+		if p.Stock < limit {
+			bajoStock = append(bajoStock, p)
+		}
+	}
+	return bajoStock, nil
+}
+
+var _ inventario.Repository = (*Repository)(nil)
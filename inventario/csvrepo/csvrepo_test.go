@@ -0,0 +1,40 @@
+package csvrepo
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/25xabierif/PSP/inventario"
+)
+
+// TestSaveYLoadProductosRedondaViaje comprueba que LoadProductos (que ya no
+// usa reader.ReadAll) lee exactamente lo que SaveProductos escribió.
+func TestSaveYLoadProductosRedondaViaje(t *testing.T) {
+	invPath := filepath.Join(t.TempDir(), "inventario.txt")
+	r := New(invPath, "")
+
+	productos := []inventario.Producto{
+		{ID: "P1", Nombre: "Tornillo", Categoria: "Ferretería", Precio: 0.5, Stock: 10},
+		{ID: "P2", Nombre: "Martillo", Categoria: "Ferretería", Precio: 12, Stock: 3},
+	}
+	if err := r.SaveProductos(productos); err != nil {
+		t.Fatalf("SaveProductos: %v", err)
+	}
+
+	cargados, err := r.LoadProductos()
+	if err != nil {
+		t.Fatalf("LoadProductos: %v", err)
+	}
+	if len(cargados) != 2 || cargados[0] != productos[0] || cargados[1] != productos[1] {
+		t.Fatalf("LoadProductos = %+v, quería %+v", cargados, productos)
+	}
+}
+
+// TestLoadProductosArchivoInexistente comprueba que abrir un inventario que
+// no existe devuelve un error en vez de un panic o una lista vacía silenciosa.
+func TestLoadProductosArchivoInexistente(t *testing.T) {
+	r := New(filepath.Join(t.TempDir(), "no-existe.txt"), "")
+	if _, err := r.LoadProductos(); err == nil {
+		t.Fatal("LoadProductos sobre un archivo inexistente debería devolver error")
+	}
+}
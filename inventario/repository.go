@@ -0,0 +1,15 @@
+package inventario
+
+// Repository abstrae el almacenamiento del inventario para que el resto del
+// programa no dependa de si los datos viven en un CSV, una base SQL u otra
+// cosa. Cada driver (csvrepo, sqlrepo, ...) implementa esta interfaz.
+type Repository interface {
+	// LoadProductos devuelve el inventario completo.
+	LoadProductos() ([]Producto, error)
+	// SaveProductos persiste el inventario completo, sustituyendo el anterior.
+	SaveProductos([]Producto) error
+	// AppendTransaccion registra una transacción ya aplicada, para auditoría.
+	AppendTransaccion(Transaccion) error
+	// QueryBajoStock devuelve los productos cuyo Stock es menor que 'limit'.
+	QueryBajoStock(limit int) ([]Producto, error)
+}
@@ -0,0 +1,136 @@
+// Package sqlrepo implementa inventario.Repository sobre database/sql, con
+// SQLite como motor: Open siempre usa el driver sqlite3 y no hay forma de
+// elegir otro. A diferencia de csvrepo, permite añadir o
+// consultar productos sin reescribir el archivo entero en cada ejecución.
+package sqlrepo
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/25xabierif/PSP/inventario"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS productos (
+	id        TEXT PRIMARY KEY,
+	nombre    TEXT NOT NULL,
+	categoria TEXT NOT NULL,
+	precio    REAL NOT NULL,
+	stock     INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS transacciones (
+	seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+	tipo        TEXT NOT NULL,
+	id_producto TEXT NOT NULL,
+	cantidad    INTEGER NOT NULL,
+	fecha       TEXT NOT NULL
+);
+`
+
+// Repository opera sobre la base SQLite abierta por Open, con consultas
+// parametrizadas con marcadores de posición '?'.
+type Repository struct {
+	db *sql.DB
+}
+
+// Open abre (o crea) la base de datos en dsn y asegura el esquema.
+func Open(dsn string) (*Repository, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir la base de datos: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("no se pudo preparar el esquema: %w", err)
+	}
+	return &Repository{db: db}, nil
+}
+
+// Close libera la conexión subyacente.
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+func (r *Repository) LoadProductos() ([]inventario.Producto, error) {
+	rows, err := r.db.Query(`SELECT id, nombre, categoria, precio, stock FROM productos ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando productos: %w", err)
+	}
+	defer rows.Close()
+
+	var productos []inventario.Producto
+	for rows.Next() {
+		var p inventario.Producto
+		if err := rows.Scan(&p.ID, &p.Nombre, &p.Categoria, &p.Precio, &p.Stock); err != nil {
+			return nil, fmt.Errorf("error leyendo fila de productos: %w", err)
+		}
+		productos = append(productos, p)
+	}
+	return productos, rows.Err()
+}
+
+// SaveProductos hace upsert de cada producto dentro de una única transacción,
+// así una inserción parcial no deja el inventario a medio actualizar.
+func (r *Repository) SaveProductos(productos []inventario.Producto) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("no se pudo iniciar transacción: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO productos (id, nombre, categoria, precio, stock) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET nombre=excluded.nombre, categoria=excluded.categoria,
+			precio=excluded.precio, stock=excluded.stock
+	`)
+	if err != nil {
+		return fmt.Errorf("no se pudo preparar el upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, p := range productos {
+		if _, err := stmt.Exec(p.ID, p.Nombre, p.Categoria, p.Precio, p.Stock); err != nil {
+			return fmt.Errorf("error guardando producto %s: %w", p.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) AppendTransaccion(t inventario.Transaccion) error {
+	_, err := r.db.Exec(
+		`INSERT INTO transacciones (tipo, id_producto, cantidad, fecha) VALUES (?, ?, ?, ?)`,
+		t.Tipo, t.IDProducto, t.Cantidad, t.Fecha,
+	)
+	if err != nil {
+		return fmt.Errorf("error registrando transacción: %w", err)
+	}
+	return nil
+}
+
+// QueryBajoStock delega el filtrado en SQLite en vez de traer todo a Go,
+// análogo a un "select * from productos where stock < ?" parametrizado.
+func (r *Repository) QueryBajoStock(limit int) ([]inventario.Producto, error) {
+	rows, err := r.db.Query(
+		`SELECT id, nombre, categoria, precio, stock FROM productos WHERE stock < ? ORDER BY stock`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando bajo stock: %w", err)
+	}
+	defer rows.Close()
+
+	var productos []inventario.Producto
+	for rows.Next() {
+		var p inventario.Producto
+		if err := rows.Scan(&p.ID, &p.Nombre, &p.Categoria, &p.Precio, &p.Stock); err != nil {
+			return nil, fmt.Errorf("error leyendo fila de bajo stock: %w", err)
+		}
+		productos = append(productos, p)
+	}
+	return productos, rows.Err()
+}
+
+var _ inventario.Repository = (*Repository)(nil)
@@ -0,0 +1,49 @@
+package sqlrepo
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/25xabierif/PSP/inventario"
+)
+
+// TestSaveYLoadProductosRedondaViaje comprueba el upsert básico: guardar y
+// volver a leer devuelve los mismos productos, y una segunda escritura
+// actualiza en vez de duplicar filas.
+func TestSaveYLoadProductosRedondaViaje(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "inventario.db")
+	repo, err := Open(dsn)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer repo.Close()
+
+	productos := []inventario.Producto{
+		{ID: "P1", Nombre: "Tornillo", Categoria: "Ferretería", Precio: 0.5, Stock: 10},
+		{ID: "P2", Nombre: "Martillo", Categoria: "Ferretería", Precio: 12, Stock: 3},
+	}
+	if err := repo.SaveProductos(productos); err != nil {
+		t.Fatalf("SaveProductos: %v", err)
+	}
+
+	cargados, err := repo.LoadProductos()
+	if err != nil {
+		t.Fatalf("LoadProductos: %v", err)
+	}
+	if len(cargados) != 2 || cargados[0].Stock != 10 || cargados[1].Stock != 3 {
+		t.Fatalf("LoadProductos = %+v, quería los 2 productos sembrados", cargados)
+	}
+
+	// Actualizar P1 no debe crear una fila nueva.
+	productos[0].Stock = 6
+	if err := repo.SaveProductos(productos[:1]); err != nil {
+		t.Fatalf("SaveProductos (update): %v", err)
+	}
+	cargados, err = repo.LoadProductos()
+	if err != nil {
+		t.Fatalf("LoadProductos tras update: %v", err)
+	}
+	if len(cargados) != 2 || cargados[0].Stock != 6 {
+		t.Fatalf("LoadProductos tras update = %+v, quería P1 con Stock=6 y sin filas nuevas", cargados)
+	}
+}
@@ -0,0 +1,35 @@
+// Package inventario contiene el dominio compartido (Producto, Transaccion) y
+// el contrato Repository que desacopla el resto del programa de cómo se
+// guarda el inventario (CSV, SQL, ...).
+package inventario
+
+import "fmt"
+
+// Producto es un artículo del inventario.
+type Producto struct {
+	ID        string
+	Nombre    string
+	Categoria string
+	Precio    float64
+	Stock     int
+}
+
+func (p Producto) String() string {
+	return fmt.Sprintf("ID: %s | %s | Stock actual: %d unidades", p.ID, p.Nombre, p.Stock)
+}
+
+// Transaccion es un movimiento (VENTA, COMPRA o DEVOLUCION) sobre un Producto.
+// ID es opcional: si no viene informado (por ejemplo, al leerlo de un CSV que
+// no tiene esa columna), se deriva de los demás campos para poder detectar
+// duplicados sin que el operador tenga que numerarlas a mano.
+type Transaccion struct {
+	ID         string
+	Tipo       string
+	IDProducto string
+	Cantidad   int
+	Fecha      string
+}
+
+func (t Transaccion) String() string {
+	return fmt.Sprintf("%s,%s,%d,%s", t.Tipo, t.IDProducto, t.Cantidad, t.Fecha)
+}